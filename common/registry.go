@@ -0,0 +1,57 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+// RegistryType identifies the kind of backend a Registry is served from,
+// for example "github", "gcs" or "s3".
+type RegistryType string
+
+const GithubRegistryType RegistryType = "github"
+
+// RegistryFormat describes the layout of templates or packages within a
+// registry. A registry's format is the ";"-joined combination of one
+// versioning descriptor (UnversionedRegistry or VersionedRegistry) and one
+// layout descriptor (OneLevelRegistry or CollectionRegistry).
+type RegistryFormat string
+
+const (
+	UnversionedRegistry RegistryFormat = "unversioned"
+	VersionedRegistry   RegistryFormat = "versioned"
+	OneLevelRegistry    RegistryFormat = "onelevel"
+	CollectionRegistry  RegistryFormat = "collection"
+)
+
+// Registry describes a single template or package registry known to the
+// deployment manager.
+type Registry struct {
+	Name   string
+	Type   RegistryType
+	URL    string
+	Format RegistryFormat
+
+	// Credentials names the credential this registry authenticates with,
+	// resolved by an AuthProvider. Empty means the registry is accessed
+	// anonymously.
+	Credentials string
+}
+
+// RegistryService manages the set of registries known to the deployment
+// manager.
+type RegistryService interface {
+	Get(name string) (*Registry, error)
+	GetByURL(URL string) (*Registry, error)
+}
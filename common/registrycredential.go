@@ -0,0 +1,39 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+// BasicAuthCredential is a plain username/password pair.
+type BasicAuthCredential struct {
+	Username string
+	Password string
+}
+
+// RegistryCredential holds the authentication material for a single named
+// credential, as referenced by Registry.Credentials. Exactly one of its
+// fields is expected to be set; which one determines how a Registry's HTTP
+// client authenticates.
+type RegistryCredential struct {
+	BasicAuth                  BasicAuthCredential
+	OAuthToken                 string
+	GithubAppInstallationToken string
+}
+
+// SecretProvider resolves a named credential to its RegistryCredential,
+// typically by reading a Kubernetes secret.
+type SecretProvider interface {
+	GetRegistryCredential(name string) (RegistryCredential, error)
+}
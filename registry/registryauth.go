@@ -0,0 +1,134 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"github.com/kubernetes/deployment-manager/common"
+
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// AuthProvider returns an authenticated *http.Client for a registry's
+// credentials. A nil *http.Client with a nil error means the registry
+// should be accessed anonymously.
+type AuthProvider interface {
+	GetClient(cr common.Registry) (*http.Client, error)
+}
+
+// inmemAuthProvider resolves common.Registry.Credentials against an
+// in-memory map of named credentials. It is primarily useful for tests and
+// for small, static deployments.
+type inmemAuthProvider struct {
+	sync.RWMutex
+	credentials map[string]common.RegistryCredential
+}
+
+// NewInmemAuthProvider returns an AuthProvider backed by an in-memory map of
+// credentials, keyed by credential name.
+func NewInmemAuthProvider() AuthProvider {
+	return &inmemAuthProvider{credentials: make(map[string]common.RegistryCredential)}
+}
+
+// SetCredential registers the credential to be returned for the given name.
+func (p *inmemAuthProvider) SetCredential(name string, c common.RegistryCredential) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.credentials[name] = c
+}
+
+func (p *inmemAuthProvider) GetClient(cr common.Registry) (*http.Client, error) {
+	if cr.Credentials == "" {
+		return nil, nil
+	}
+
+	p.RLock()
+	defer p.RUnlock()
+
+	c, ok := p.credentials[cr.Credentials]
+	if !ok {
+		return nil, fmt.Errorf("no credentials registered under name %s", cr.Credentials)
+	}
+
+	return clientForCredential(c)
+}
+
+// kubernetesSecretAuthProvider resolves common.Registry.Credentials by
+// fetching a Kubernetes secret through a common.SecretProvider and decoding
+// it into a common.RegistryCredential.
+type kubernetesSecretAuthProvider struct {
+	secrets common.SecretProvider
+}
+
+// NewKubernetesSecretAuthProvider returns an AuthProvider that looks up
+// cr.Credentials as the name of a Kubernetes secret, using secrets to
+// retrieve it.
+func NewKubernetesSecretAuthProvider(secrets common.SecretProvider) AuthProvider {
+	return &kubernetesSecretAuthProvider{secrets: secrets}
+}
+
+func (p *kubernetesSecretAuthProvider) GetClient(cr common.Registry) (*http.Client, error) {
+	if cr.Credentials == "" {
+		return nil, nil
+	}
+
+	c, err := p.secrets.GetRegistryCredential(cr.Credentials)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch credentials %s: %s", cr.Credentials, err)
+	}
+
+	return clientForCredential(c)
+}
+
+// clientForCredential builds the *http.Client appropriate for c's
+// authentication method.
+func clientForCredential(c common.RegistryCredential) (*http.Client, error) {
+	switch {
+	case c.BasicAuth.Username != "":
+		return &http.Client{Transport: &basicAuthTransport{username: c.BasicAuth.Username, password: c.BasicAuth.Password}}, nil
+	case c.OAuthToken != "":
+		return &http.Client{Transport: &tokenAuthTransport{token: c.OAuthToken}}, nil
+	case c.GithubAppInstallationToken != "":
+		return &http.Client{Transport: &tokenAuthTransport{token: c.GithubAppInstallationToken}}, nil
+	default:
+		return nil, fmt.Errorf("credential has no recognized authentication method set")
+	}
+}
+
+// basicAuthTransport adds HTTP basic auth to every request.
+type basicAuthTransport struct {
+	username string
+	password string
+}
+
+func (t *basicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.SetBasicAuth(t.username, t.password)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// tokenAuthTransport adds an "Authorization: token <token>" header to every
+// request, suitable for OAuth tokens and GitHub App installation tokens alike.
+type tokenAuthTransport struct {
+	token string
+}
+
+func (t *tokenAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.Header.Set("Authorization", "token "+t.token)
+	return http.DefaultTransport.RoundTrip(req)
+}
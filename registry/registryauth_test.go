@@ -0,0 +1,160 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kubernetes/deployment-manager/common"
+)
+
+func TestInmemAuthProviderAnonymous(t *testing.T) {
+	p := NewInmemAuthProvider()
+
+	client, err := p.GetClient(common.Registry{Name: "anon"})
+	if err != nil {
+		t.Fatalf("GetClient for a registry with no credentials: %s", err)
+	}
+	if client != nil {
+		t.Errorf("GetClient for a registry with no credentials = %v, want nil", client)
+	}
+}
+
+func TestInmemAuthProviderMissingCredential(t *testing.T) {
+	p := NewInmemAuthProvider()
+
+	if _, err := p.GetClient(common.Registry{Name: "r", Credentials: "does-not-exist"}); err == nil {
+		t.Error("GetClient for an unregistered credential name: expected error, got nil")
+	}
+}
+
+func TestInmemAuthProviderBasicAuth(t *testing.T) {
+	p := NewInmemAuthProvider().(*inmemAuthProvider)
+	p.SetCredential("creds", common.RegistryCredential{
+		BasicAuth: common.BasicAuthCredential{Username: "alice", Password: "hunter2"},
+	})
+
+	client, err := p.GetClient(common.Registry{Name: "r", Credentials: "creds"})
+	if err != nil {
+		t.Fatalf("GetClient: %s", err)
+	}
+
+	transport, ok := client.Transport.(*basicAuthTransport)
+	if !ok {
+		t.Fatalf("client.Transport is %T, want *basicAuthTransport", client.Transport)
+	}
+	if transport.username != "alice" || transport.password != "hunter2" {
+		t.Errorf("basicAuthTransport = {%s, %s}, want {alice, hunter2}", transport.username, transport.password)
+	}
+}
+
+func TestInmemAuthProviderOAuthToken(t *testing.T) {
+	p := NewInmemAuthProvider().(*inmemAuthProvider)
+	p.SetCredential("creds", common.RegistryCredential{OAuthToken: "oauth-tok"})
+
+	client, err := p.GetClient(common.Registry{Name: "r", Credentials: "creds"})
+	if err != nil {
+		t.Fatalf("GetClient: %s", err)
+	}
+
+	transport, ok := client.Transport.(*tokenAuthTransport)
+	if !ok {
+		t.Fatalf("client.Transport is %T, want *tokenAuthTransport", client.Transport)
+	}
+	if transport.token != "oauth-tok" {
+		t.Errorf("tokenAuthTransport.token = %s, want oauth-tok", transport.token)
+	}
+}
+
+func TestInmemAuthProviderGithubAppToken(t *testing.T) {
+	p := NewInmemAuthProvider().(*inmemAuthProvider)
+	p.SetCredential("creds", common.RegistryCredential{GithubAppInstallationToken: "ghs_tok"})
+
+	client, err := p.GetClient(common.Registry{Name: "r", Credentials: "creds"})
+	if err != nil {
+		t.Fatalf("GetClient: %s", err)
+	}
+
+	transport, ok := client.Transport.(*tokenAuthTransport)
+	if !ok {
+		t.Fatalf("client.Transport is %T, want *tokenAuthTransport", client.Transport)
+	}
+	if transport.token != "ghs_tok" {
+		t.Errorf("tokenAuthTransport.token = %s, want ghs_tok", transport.token)
+	}
+}
+
+func TestClientForCredentialNoRecognizedMethod(t *testing.T) {
+	if _, err := clientForCredential(common.RegistryCredential{}); err == nil {
+		t.Error("clientForCredential with no authentication method set: expected error, got nil")
+	}
+}
+
+// fakeSecretProvider is a common.SecretProvider backed by an in-memory map.
+type fakeSecretProvider struct {
+	secrets map[string]common.RegistryCredential
+}
+
+func (f *fakeSecretProvider) GetRegistryCredential(name string) (common.RegistryCredential, error) {
+	c, ok := f.secrets[name]
+	if !ok {
+		return common.RegistryCredential{}, fmt.Errorf("no secret named %s", name)
+	}
+
+	return c, nil
+}
+
+func TestKubernetesSecretAuthProvider(t *testing.T) {
+	secrets := &fakeSecretProvider{secrets: map[string]common.RegistryCredential{
+		"my-secret": {OAuthToken: "secret-tok"},
+	}}
+	p := NewKubernetesSecretAuthProvider(secrets)
+
+	client, err := p.GetClient(common.Registry{Name: "r", Credentials: "my-secret"})
+	if err != nil {
+		t.Fatalf("GetClient: %s", err)
+	}
+
+	transport, ok := client.Transport.(*tokenAuthTransport)
+	if !ok {
+		t.Fatalf("client.Transport is %T, want *tokenAuthTransport", client.Transport)
+	}
+	if transport.token != "secret-tok" {
+		t.Errorf("tokenAuthTransport.token = %s, want secret-tok", transport.token)
+	}
+}
+
+func TestKubernetesSecretAuthProviderAnonymous(t *testing.T) {
+	p := NewKubernetesSecretAuthProvider(&fakeSecretProvider{})
+
+	client, err := p.GetClient(common.Registry{Name: "anon"})
+	if err != nil {
+		t.Fatalf("GetClient for a registry with no credentials: %s", err)
+	}
+	if client != nil {
+		t.Errorf("GetClient for a registry with no credentials = %v, want nil", client)
+	}
+}
+
+func TestKubernetesSecretAuthProviderMissingSecret(t *testing.T) {
+	p := NewKubernetesSecretAuthProvider(&fakeSecretProvider{})
+
+	if _, err := p.GetClient(common.Registry{Name: "r", Credentials: "does-not-exist"}); err == nil {
+		t.Error("GetClient for a missing secret: expected error, got nil")
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"github.com/kubernetes/deployment-manager/common"
+	"github.com/kubernetes/deployment-manager/util"
+
+	"fmt"
+	"sync"
+)
+
+// RegistryBackend constructs a Registry for a particular common.Registry.Type,
+// such as "github", "gcs" or "s3". Backends are registered with
+// RegisterRegistryBackend and looked up by registryProvider when it needs to
+// instantiate a Registry for a common.Registry it doesn't already have cached.
+type RegistryBackend interface {
+	NewRegistry(cr common.Registry) (Registry, error)
+}
+
+// RegistryBackendFactory is a convenience adapter that lets an ordinary
+// function satisfy the RegistryBackend interface.
+type RegistryBackendFactory func(cr common.Registry) (Registry, error)
+
+// NewRegistry calls f(cr).
+func (f RegistryBackendFactory) NewRegistry(cr common.Registry) (Registry, error) {
+	return f(cr)
+}
+
+// RegistryURLMatcher recognizes and parses the short type strings understood
+// by a given RegistryBackend, for example:
+//
+//	github.com/owner/repo/qualifier/type:version
+//	gs://bucket/qualifier/type:version
+type RegistryURLMatcher interface {
+	// Matches returns true if t is a short type string for this backend.
+	Matches(t string) bool
+
+	// Parse extracts the qualifier, type name and version from t. Parse is
+	// only ever called after Matches has returned true for t.
+	Parse(t string) (qualifier, typeName, version string, err error)
+}
+
+var (
+	backendsMu  sync.RWMutex
+	backends    = map[string]RegistryBackend{}
+	urlMatchers = map[string]RegistryURLMatcher{}
+)
+
+// RegisterRegistryBackend makes a RegistryBackend available under typeName,
+// which corresponds to common.Registry.Type (for example "github", "gcs" or
+// "s3"). If matcher is non-nil, it is also registered so that
+// GetDownloadURLs can recognize short type strings belonging to this
+// backend. RegisterRegistryBackend is typically called from an init function
+// and is safe for concurrent use.
+func RegisterRegistryBackend(typeName string, backend RegistryBackend, matcher RegistryURLMatcher) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	backends[typeName] = backend
+	if matcher != nil {
+		urlMatchers[typeName] = matcher
+	}
+}
+
+// getRegistryBackend returns the RegistryBackend registered for typeName, if
+// any.
+func getRegistryBackend(typeName string) (RegistryBackend, bool) {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+
+	b, ok := backends[typeName]
+	return b, ok
+}
+
+// matchRegisteredURL finds the registered RegistryURLMatcher that claims t,
+// and returns the backend type name together with the parsed qualifier,
+// type name and version.
+func matchRegisteredURL(t string) (typeName, qualifier, typeStr, version string, ok bool) {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+
+	for name, m := range urlMatchers {
+		if !m.Matches(t) {
+			continue
+		}
+
+		qualifier, typeStr, version, err := m.Parse(t)
+		if err != nil {
+			continue
+		}
+
+		return name, qualifier, typeStr, version, true
+	}
+
+	return "", "", "", "", false
+}
+
+// BackendShortTypeToDownloadURLs resolves a short type string recognized by
+// a registered RegistryURLMatcher into downloadable URLs, dispatching
+// through the RegistryBackend registered under the same name.
+func BackendShortTypeToDownloadURLs(rp RegistryProvider, t string) ([]string, error) {
+	typeName, qualifier, typeStr, version, ok := matchRegisteredURL(t)
+	if !ok {
+		return nil, fmt.Errorf("no registered registry backend recognizes %s", t)
+	}
+
+	resolve := func() ([]string, string, error) {
+		r, err := rp.GetRegistryByShortURL(t)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if r == nil {
+			return nil, "", fmt.Errorf("cannot get %s registry for %s", typeName, t)
+		}
+
+		tt, err := NewType(qualifier, typeStr, version)
+		if err != nil {
+			return nil, "", err
+		}
+
+		urls, err := r.GetDownloadURLs(tt)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return util.ConvertURLsToStrings(urls), r.GetRegistryName(), nil
+	}
+
+	if c := getActiveCache(); c != nil {
+		return c.ResolveDownloadURLs(t, resolve)
+	}
+
+	urls, _, err := resolve()
+	return urls, err
+}
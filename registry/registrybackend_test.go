@@ -0,0 +1,169 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/kubernetes/deployment-manager/common"
+)
+
+const fakeBackendType = "faketype-for-tests"
+
+// fakeRegistry is a minimal Registry used across this package's tests. It
+// implements only the methods the registry package itself calls on a
+// Registry value.
+type fakeRegistry struct {
+	name     string
+	shortURL string
+}
+
+func (f *fakeRegistry) GetRegistryName() string     { return f.name }
+func (f *fakeRegistry) GetRegistryShortURL() string { return f.shortURL }
+
+func (f *fakeRegistry) GetDownloadURLs(t *Type) ([]*url.URL, error) {
+	u, err := url.Parse(f.shortURL + "/" + t.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*url.URL{u}, nil
+}
+
+// fakeRegistryService is a common.RegistryService backed by in-memory maps,
+// used in place of the external NewInmemRegistryService so these tests don't
+// depend on anything outside this diff.
+type fakeRegistryService struct {
+	mu     sync.Mutex
+	byName map[string]*common.Registry
+	byURL  map[string]*common.Registry
+}
+
+func (s *fakeRegistryService) Get(name string) (*common.Registry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cr, ok := s.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("no such registry: %s", name)
+	}
+
+	return cr, nil
+}
+
+func (s *fakeRegistryService) GetByURL(URL string) (*common.Registry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cr, ok := s.byURL[URL]
+	if !ok {
+		return nil, fmt.Errorf("no registry for url: %s", URL)
+	}
+
+	return cr, nil
+}
+
+// fakeURLMatcher recognizes "faketype://qualifier/name:version".
+type fakeURLMatcher struct{}
+
+var fakeURLPattern = regexp.MustCompile(`^faketype://([^/]+)/([^:]+):(.+)$`)
+
+func (fakeURLMatcher) Matches(t string) bool {
+	return fakeURLPattern.MatchString(t)
+}
+
+func (fakeURLMatcher) Parse(t string) (qualifier, typeName, version string, err error) {
+	m := fakeURLPattern.FindStringSubmatch(t)
+	if m == nil {
+		return "", "", "", fmt.Errorf("not a faketype url: %s", t)
+	}
+
+	return m[1], m[2], m[3], nil
+}
+
+func TestRegisterAndGetRegistryBackend(t *testing.T) {
+	backend := RegistryBackendFactory(func(cr common.Registry) (Registry, error) {
+		return &fakeRegistry{name: cr.Name, shortURL: cr.URL}, nil
+	})
+
+	RegisterRegistryBackend(fakeBackendType, backend, fakeURLMatcher{})
+
+	got, ok := getRegistryBackend(fakeBackendType)
+	if !ok {
+		t.Fatalf("getRegistryBackend(%s): not found after registration", fakeBackendType)
+	}
+
+	r, err := got.NewRegistry(common.Registry{Name: "fake-registry", URL: "faketype://storage/redis:v1"})
+	if err != nil {
+		t.Fatalf("NewRegistry: %s", err)
+	}
+	if r.GetRegistryName() != "fake-registry" {
+		t.Errorf("GetRegistryName() = %s, want fake-registry", r.GetRegistryName())
+	}
+}
+
+func TestGetRegistryBackendUnknownType(t *testing.T) {
+	if _, ok := getRegistryBackend("no-such-backend-type"); ok {
+		t.Error("getRegistryBackend for an unregistered type: got ok=true, want false")
+	}
+}
+
+func TestMatchRegisteredURL(t *testing.T) {
+	RegisterRegistryBackend(fakeBackendType, RegistryBackendFactory(func(cr common.Registry) (Registry, error) {
+		return &fakeRegistry{name: cr.Name, shortURL: cr.URL}, nil
+	}), fakeURLMatcher{})
+
+	typeName, qualifier, typeStr, version, ok := matchRegisteredURL("faketype://storage/redis:v1")
+	if !ok {
+		t.Fatal("matchRegisteredURL: expected a match, got none")
+	}
+	if typeName != fakeBackendType || qualifier != "storage" || typeStr != "redis" || version != "v1" {
+		t.Errorf("matchRegisteredURL = (%s, %s, %s, %s), want (%s, storage, redis, v1)",
+			typeName, qualifier, typeStr, version, fakeBackendType)
+	}
+
+	if _, _, _, _, ok := matchRegisteredURL("github.com/owner/repo/storage/redis:v1"); ok {
+		t.Error("matchRegisteredURL matched a url no registered matcher recognizes")
+	}
+}
+
+func TestBackendShortTypeToDownloadURLs(t *testing.T) {
+	RegisterRegistryBackend(fakeBackendType, RegistryBackendFactory(func(cr common.Registry) (Registry, error) {
+		return &fakeRegistry{name: cr.Name, shortURL: cr.URL}, nil
+	}), fakeURLMatcher{})
+
+	rs := &fakeRegistryService{
+		byURL: map[string]*common.Registry{
+			"faketype://storage/redis:v1": {Name: "fake-registry", Type: fakeBackendType, URL: "faketype://storage/redis:v1"},
+		},
+	}
+
+	rp := NewRegistryProvider(rs, nil, nil)
+	defer rp.Close()
+
+	urls, err := BackendShortTypeToDownloadURLs(rp, "faketype://storage/redis:v1")
+	if err != nil {
+		t.Fatalf("BackendShortTypeToDownloadURLs: %s", err)
+	}
+	if len(urls) != 1 {
+		t.Fatalf("BackendShortTypeToDownloadURLs = %v, want exactly one URL", urls)
+	}
+}
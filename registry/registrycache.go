@@ -0,0 +1,250 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RegistryCache sits in front of a RegistryProvider, caching resolved
+// download URLs and fetched template bytes on disk so that repeated
+// deployments over slow links become near-instant. Download URL entries are
+// keyed by the (registry, type, version) short type string that produced
+// them; template bytes are keyed by their download URL. Every entry records
+// the registry it came from so Invalidate can evict exactly that registry's
+// entries, URL and blob alike. Entries expire after TTL. An expired blob
+// entry is revalidated against the origin server using HTTP
+// ETag/If-None-Match rather than re-downloaded outright; an expired URL
+// entry is resolved again.
+type RegistryCache struct {
+	mu        sync.Mutex
+	dir       string
+	ttl       time.Duration
+	client    *http.Client
+	urlCache  map[string]*urlCacheEntry
+	blobCache map[string]*blobCacheEntry
+}
+
+// urlCacheEntry is the cached result of resolving a short type string to
+// its download URLs. It is replaced, never mutated, once published into
+// RegistryCache.urlCache, so reading its fields after an unlocked lookup is
+// race-free.
+type urlCacheEntry struct {
+	urls         []string
+	registryName string
+	cachedAt     time.Time
+}
+
+// blobCacheEntry is the cached, on-disk copy of a fetched template. Like
+// urlCacheEntry, it is replaced, never mutated, once published into
+// RegistryCache.blobCache.
+type blobCacheEntry struct {
+	path         string
+	registryName string
+	etag         string
+	cachedAt     time.Time
+}
+
+var (
+	activeCacheMu sync.RWMutex
+	activeCache   *RegistryCache
+)
+
+// SetActiveCache installs c as the cache consulted by GetDownloadURLs,
+// ShortTypeToDownloadURLs and ShortTypeToPackageDownloadURLs. Passing nil
+// disables caching; this is also the default.
+func SetActiveCache(c *RegistryCache) {
+	activeCacheMu.Lock()
+	defer activeCacheMu.Unlock()
+
+	activeCache = c
+}
+
+// getActiveCache returns the cache installed by SetActiveCache, if any.
+func getActiveCache() *RegistryCache {
+	activeCacheMu.RLock()
+	defer activeCacheMu.RUnlock()
+
+	return activeCache
+}
+
+// NewRegistryCache returns a RegistryCache that stores fetched template
+// bytes under dir and treats cached entries as fresh for ttl. A ttl of zero
+// or less disables expiry: cached entries are then served without ever
+// being resolved again or revalidated via ETag.
+func NewRegistryCache(dir string, ttl time.Duration) *RegistryCache {
+	return &RegistryCache{
+		dir:       dir,
+		ttl:       ttl,
+		client:    http.DefaultClient,
+		urlCache:  make(map[string]*urlCacheEntry),
+		blobCache: make(map[string]*blobCacheEntry),
+	}
+}
+
+// ResolveDownloadURLs returns the cached download URLs previously resolved
+// for the short type string t, if still fresh. Otherwise it calls resolve,
+// which must return the resolved URLs together with the name of the
+// registry that produced them, caches the result, and returns it.
+func (c *RegistryCache) ResolveDownloadURLs(t string, resolve func() (urls []string, registryName string, err error)) ([]string, error) {
+	c.mu.Lock()
+	entry, ok := c.urlCache[t]
+	c.mu.Unlock()
+
+	if ok && c.fresh(entry.cachedAt) {
+		return entry.urls, nil
+	}
+
+	urls, registryName, err := resolve()
+	if err != nil {
+		if ok {
+			// Origin is temporarily unreachable: serve the stale entry
+			// rather than fail a deployment outright.
+			return entry.urls, nil
+		}
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.urlCache[t] = &urlCacheEntry{urls: urls, registryName: registryName, cachedAt: time.Now()}
+	c.mu.Unlock()
+
+	return urls, nil
+}
+
+// FetchTemplate returns the cached bytes for url if they are fresh,
+// otherwise downloads them, revalidating with If-None-Match when a prior
+// ETag is known, and writes the result to disk under c.dir. registryName is
+// recorded alongside the cached bytes so Invalidate can evict them later.
+func (c *RegistryCache) FetchTemplate(registryName, url string) ([]byte, error) {
+	c.mu.Lock()
+	entry, ok := c.blobCache[url]
+	c.mu.Unlock()
+
+	if ok && c.fresh(entry.cachedAt) {
+		if b, err := ioutil.ReadFile(entry.path); err == nil {
+			return b, nil
+		}
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok && entry.etag != "" {
+		req.Header.Set("If-None-Match", entry.etag)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && ok {
+		// Refresh just the freshness timestamp. entry is replaced wholesale
+		// rather than mutated in place, since other goroutines may be
+		// reading the entry concurrently without holding c.mu.
+		refreshed := &blobCacheEntry{path: entry.path, registryName: entry.registryName, etag: entry.etag, cachedAt: time.Now()}
+
+		c.mu.Lock()
+		c.blobCache[url] = refreshed
+		c.mu.Unlock()
+
+		return ioutil.ReadFile(refreshed.path)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := c.writeToDisk(url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.blobCache[url] = &blobCacheEntry{path: path, registryName: registryName, etag: resp.Header.Get("ETag"), cachedAt: time.Now()}
+	c.mu.Unlock()
+
+	return body, nil
+}
+
+// Invalidate evicts every cached entry, URL or blob, associated with the
+// named registry.
+func (c *RegistryCache) Invalidate(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.urlCache {
+		if entry.registryName == name {
+			delete(c.urlCache, key)
+		}
+	}
+
+	for key, entry := range c.blobCache {
+		if entry.registryName == name {
+			delete(c.blobCache, key)
+		}
+	}
+}
+
+// Purge evicts every cached entry.
+func (c *RegistryCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.urlCache = make(map[string]*urlCacheEntry)
+	c.blobCache = make(map[string]*blobCacheEntry)
+}
+
+func (c *RegistryCache) fresh(cachedAt time.Time) bool {
+	if c.ttl <= 0 {
+		return true
+	}
+
+	return time.Since(cachedAt) < c.ttl
+}
+
+func (c *RegistryCache) writeToDisk(url string, body []byte) (string, error) {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(c.dir, cacheFileName(url))
+	if err := ioutil.WriteFile(path, body, 0644); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// cacheFileName derives a filesystem-safe cache file name from a download
+// URL.
+func cacheFileName(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolveDownloadURLsCachesUntilStale(t *testing.T) {
+	c := NewRegistryCache(t.TempDir(), time.Hour)
+
+	calls := 0
+	resolve := func() ([]string, string, error) {
+		calls++
+		return []string{"https://example.com/redis.yaml"}, "redis-registry", nil
+	}
+
+	urls, err := c.ResolveDownloadURLs("github.com/o/r/storage/redis:v1", resolve)
+	if err != nil {
+		t.Fatalf("ResolveDownloadURLs: %s", err)
+	}
+	if len(urls) != 1 || urls[0] != "https://example.com/redis.yaml" {
+		t.Fatalf("ResolveDownloadURLs = %v, want [https://example.com/redis.yaml]", urls)
+	}
+	if calls != 1 {
+		t.Fatalf("resolve called %d times, want 1", calls)
+	}
+
+	if _, err := c.ResolveDownloadURLs("github.com/o/r/storage/redis:v1", resolve); err != nil {
+		t.Fatalf("ResolveDownloadURLs: %s", err)
+	}
+	if calls != 1 {
+		t.Errorf("resolve called %d times on a fresh entry, want 1 (cache hit)", calls)
+	}
+}
+
+func TestResolveDownloadURLsServesStaleOnResolveError(t *testing.T) {
+	c := NewRegistryCache(t.TempDir(), time.Hour)
+
+	good := func() ([]string, string, error) {
+		return []string{"https://example.com/redis.yaml"}, "redis-registry", nil
+	}
+	if _, err := c.ResolveDownloadURLs("github.com/o/r/storage/redis:v1", good); err != nil {
+		t.Fatalf("ResolveDownloadURLs: %s", err)
+	}
+
+	// Age the entry past the TTL so the next lookup treats it as stale.
+	c.urlCache["github.com/o/r/storage/redis:v1"].cachedAt = time.Now().Add(-2 * time.Hour)
+
+	failing := func() ([]string, string, error) {
+		return nil, "", errFakeResolve
+	}
+	urls, err := c.ResolveDownloadURLs("github.com/o/r/storage/redis:v1", failing)
+	if err != nil {
+		t.Fatalf("ResolveDownloadURLs with a stale entry and a failing resolve: got error %s, want the stale entry", err)
+	}
+	if len(urls) != 1 || urls[0] != "https://example.com/redis.yaml" {
+		t.Errorf("ResolveDownloadURLs = %v, want the stale entry", urls)
+	}
+}
+
+func TestInvalidateEvictsURLAndBlobEntriesForRegistry(t *testing.T) {
+	c := NewRegistryCache(t.TempDir(), time.Hour)
+
+	c.urlCache["github.com/o/r/storage/redis:v1"] = &urlCacheEntry{
+		urls:         []string{"https://example.com/redis.yaml"},
+		registryName: "redis-registry",
+		cachedAt:     time.Now(),
+	}
+	c.urlCache["github.com/o/r/storage/mysql:v1"] = &urlCacheEntry{
+		urls:         []string{"https://example.com/mysql.yaml"},
+		registryName: "other-registry",
+		cachedAt:     time.Now(),
+	}
+	c.blobCache["https://example.com/redis.yaml"] = &blobCacheEntry{
+		path:         "/tmp/redis.yaml",
+		registryName: "redis-registry",
+		cachedAt:     time.Now(),
+	}
+	c.blobCache["https://example.com/mysql.yaml"] = &blobCacheEntry{
+		path:         "/tmp/mysql.yaml",
+		registryName: "other-registry",
+		cachedAt:     time.Now(),
+	}
+
+	c.Invalidate("redis-registry")
+
+	if _, ok := c.urlCache["github.com/o/r/storage/redis:v1"]; ok {
+		t.Error("Invalidate left a urlCache entry for the invalidated registry")
+	}
+	if _, ok := c.blobCache["https://example.com/redis.yaml"]; ok {
+		t.Error("Invalidate left a blobCache entry for the invalidated registry")
+	}
+
+	if _, ok := c.urlCache["github.com/o/r/storage/mysql:v1"]; !ok {
+		t.Error("Invalidate evicted a urlCache entry for a different registry")
+	}
+	if _, ok := c.blobCache["https://example.com/mysql.yaml"]; !ok {
+		t.Error("Invalidate evicted a blobCache entry for a different registry")
+	}
+}
+
+type fakeResolveError struct{}
+
+func (fakeResolveError) Error() string { return "resolve failed" }
+
+var errFakeResolve = fakeResolveError{}
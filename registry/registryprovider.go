@@ -20,6 +20,7 @@ import (
 	"github.com/kubernetes/deployment-manager/common"
 	"github.com/kubernetes/deployment-manager/util"
 
+	"context"
 	"fmt"
 	"net/url"
 	"regexp"
@@ -31,6 +32,12 @@ import (
 type RegistryProvider interface {
 	GetRegistryByShortURL(URL string) (Registry, error)
 	GetRegistryByName(registryName string) (Registry, error)
+
+	// Close stops watching the underlying common.RegistryService for
+	// changes, if startWatch ever started doing so. Callers that construct
+	// a RegistryProvider and do not intend to use it for the remainder of
+	// the process must call Close to avoid leaking the watch goroutine.
+	Close()
 }
 
 // GithubRegistryProvider is a factory for GithubRegistry instances.
@@ -39,57 +46,88 @@ type GithubRegistryProvider interface {
 }
 
 func NewDefaultRegistryProvider() RegistryProvider {
-	return NewRegistryProvider(nil, nil)
+	return NewRegistryProvider(nil, nil, nil)
 }
 
-func NewRegistryProvider(rs common.RegistryService, grp GithubRegistryProvider) RegistryProvider {
+// NewRegistryProvider creates a RegistryProvider. ap supplies the
+// *http.Client used to authenticate against registries that declare
+// credentials via common.Registry.Credentials; a nil ap falls back to
+// NewInmemAuthProvider with no credentials registered, under which
+// registries without credentials are accessed anonymously and a registry
+// that names credentials fails to resolve.
+func NewRegistryProvider(rs common.RegistryService, grp GithubRegistryProvider, ap AuthProvider) RegistryProvider {
 	if rs == nil {
 		rs = NewInmemRegistryService()
 	}
 
+	if ap == nil {
+		ap = NewInmemAuthProvider()
+	}
+
 	registries := make(map[string]Registry)
-	rp := &registryProvider{rs: rs, registries: registries}
+	rp := &registryProvider{rs: rs, ap: ap, registries: registries}
 	if grp == nil {
 		grp = rp
 	}
 
 	rp.grp = grp
+	rp.startWatch()
 	return rp
 }
 
+// Note: common.GithubRegistryType is deliberately not registered with
+// RegisterRegistryBackend. Unlike other registry types, github has its own
+// dedicated construction hook, GithubRegistryProvider, which predates
+// RegistryBackend and exists so callers (notably tests) can substitute
+// their own github registry construction without registering a global
+// backend. resolveRegistry special-cases common.GithubRegistryType to go
+// through rp.grp for exactly this reason; registering a backend for it
+// here would never be consulted and would only be misleading.
+
 type registryProvider struct {
 	sync.RWMutex
-	rs         common.RegistryService
-	grp        GithubRegistryProvider
-	registries map[string]Registry
+	rs          common.RegistryService
+	grp         GithubRegistryProvider
+	ap          AuthProvider
+	registries  map[string]Registry
+	watchCancel context.CancelFunc
 }
 
-func (rp registryProvider) GetRegistryByShortURL(URL string) (Registry, error) {
+func (rp *registryProvider) GetRegistryByShortURL(URL string) (Registry, error) {
 	rp.RLock()
-	defer rp.RUnlock()
-
 	result := rp.findRegistryByShortURL(URL)
-	if result == nil {
-		cr, err := rp.rs.GetByURL(URL)
-		if err != nil {
-			return nil, err
-		}
+	rp.RUnlock()
+	if result != nil {
+		return result, nil
+	}
 
-		r, err := rp.grp.GetGithubRegistry(*cr)
-		if err != nil {
-			return nil, err
-		}
+	cr, err := rp.rs.GetByURL(URL)
+	if err != nil {
+		return nil, err
+	}
+
+	rp.Lock()
+	defer rp.Unlock()
+
+	// Another goroutine may have resolved and cached this registry while
+	// we were unlocked above.
+	if result := rp.findRegistryByShortURL(URL); result != nil {
+		return result, nil
+	}
 
-		rp.registries[r.GetRegistryName()] = r
-		result = r
+	r, err := rp.resolveRegistry(*cr)
+	if err != nil {
+		return nil, err
 	}
 
-	return result, nil
+	rp.registries[r.GetRegistryName()] = r
+	return r, nil
 }
 
 // findRegistryByShortURL trims the scheme from both the supplied URL
-// and the short URL returned by GetRegistryShortURL.
-func (rp registryProvider) findRegistryByShortURL(URL string) Registry {
+// and the short URL returned by GetRegistryShortURL. Callers must hold rp's
+// read or write lock.
+func (rp *registryProvider) findRegistryByShortURL(URL string) Registry {
 	trimmed := util.TrimURLScheme(URL)
 	for _, r := range rp.registries {
 		if strings.HasPrefix(trimmed, util.TrimURLScheme(r.GetRegistryShortURL())) {
@@ -100,27 +138,52 @@ func (rp registryProvider) findRegistryByShortURL(URL string) Registry {
 	return nil
 }
 
-func (rp registryProvider) GetRegistryByName(registryName string) (Registry, error) {
+func (rp *registryProvider) GetRegistryByName(registryName string) (Registry, error) {
 	rp.RLock()
-	defer rp.RUnlock()
-
 	result, ok := rp.registries[registryName]
-	if !ok {
-		cr, err := rp.rs.Get(registryName)
-		if err != nil {
-			return nil, err
-		}
+	rp.RUnlock()
+	if ok {
+		return result, nil
+	}
 
-		r, err := rp.grp.GetGithubRegistry(*cr)
-		if err != nil {
-			return nil, err
-		}
+	cr, err := rp.rs.Get(registryName)
+	if err != nil {
+		return nil, err
+	}
 
-		rp.registries[r.GetRegistryName()] = r
-		result = r
+	rp.Lock()
+	defer rp.Unlock()
+
+	// Another goroutine may have resolved and cached this registry while
+	// we were unlocked above.
+	if result, ok := rp.registries[registryName]; ok {
+		return result, nil
 	}
 
-	return result, nil
+	r, err := rp.resolveRegistry(*cr)
+	if err != nil {
+		return nil, err
+	}
+
+	rp.registries[r.GetRegistryName()] = r
+	return r, nil
+}
+
+// resolveRegistry constructs a Registry for cr, dispatching to the
+// RegistryBackend registered for cr.Type. The github type is special-cased
+// to go through rp.grp so that GithubRegistryProvider implementations can
+// still be substituted, e.g. in tests. Callers must hold rp's write lock.
+func (rp *registryProvider) resolveRegistry(cr common.Registry) (Registry, error) {
+	if cr.Type == common.GithubRegistryType {
+		return rp.grp.GetGithubRegistry(cr)
+	}
+
+	backend, ok := getRegistryBackend(string(cr.Type))
+	if !ok {
+		return nil, fmt.Errorf("unknown registry type: %s", cr.Type)
+	}
+
+	return backend.NewRegistry(cr)
 }
 
 func ParseRegistryFormat(rf common.RegistryFormat) map[common.RegistryFormat]bool {
@@ -133,15 +196,28 @@ func ParseRegistryFormat(rf common.RegistryFormat) map[common.RegistryFormat]boo
 	return result
 }
 
-func (rp registryProvider) GetGithubRegistry(cr common.Registry) (GithubRegistry, error) {
+func (rp *registryProvider) GetGithubRegistry(cr common.Registry) (GithubRegistry, error) {
+	return newGithubRegistry(cr, rp.ap)
+}
+
+// newGithubRegistry builds the GithubRegistry for cr, authenticating its
+// HTTP client via ap when cr.Credentials is set. It backs the default
+// GithubRegistryProvider implementation; it is not registered as a
+// RegistryBackend (see the note above registryProvider).
+func newGithubRegistry(cr common.Registry, ap AuthProvider) (GithubRegistry, error) {
 	if cr.Type == common.GithubRegistryType {
+		client, err := ap.GetClient(cr)
+		if err != nil {
+			return nil, fmt.Errorf("cannot authenticate registry %s: %s", cr.Name, err)
+		}
+
 		fMap := ParseRegistryFormat(cr.Format)
 		if fMap[common.UnversionedRegistry] && fMap[common.OneLevelRegistry] {
-			return NewGithubPackageRegistry(cr.Name, cr.URL, nil)
+			return NewGithubPackageRegistry(cr.Name, cr.URL, client)
 		}
 
 		if fMap[common.VersionedRegistry] && fMap[common.CollectionRegistry] {
-			return NewGithubTemplateRegistry(cr.Name, cr.URL, nil)
+			return NewGithubTemplateRegistry(cr.Name, cr.URL, client)
 		}
 
 		return nil, fmt.Errorf("unknown registry format: %s", cr.Format)
@@ -183,6 +259,8 @@ func GetDownloadURLs(rp RegistryProvider, t string) ([]string, error) {
 		return ShortTypeToDownloadURLs(rp, t)
 	} else if IsGithubShortPackageType(t) {
 		return ShortTypeToPackageDownloadURLs(rp, t)
+	} else if _, _, _, _, ok := matchRegisteredURL(t); ok {
+		return BackendShortTypeToDownloadURLs(rp, t)
 	} else if util.IsHttpUrl(t) {
 		result, err := url.Parse(t)
 		if err != nil {
@@ -200,32 +278,57 @@ func GetDownloadURLs(rp RegistryProvider, t string) ([]string, error) {
 // github.com/owner/repo/qualifier/type:version
 // for example:
 // github.com/kubernetes/application-dm-templates/storage/redis:v1
+//
+// version may also be a semver range such as "^1.2", "~1.2.3" or "*", in
+// which case it is resolved against the registry's available versions via
+// ResolveVersionConstraint before the download URLs are fetched.
 func ShortTypeToDownloadURLs(rp RegistryProvider, t string) ([]string, error) {
 	m := TemplateRegistryMatcher.FindStringSubmatch(t)
 	if len(m) != 6 {
 		return nil, fmt.Errorf("cannot parse short github url: %s", t)
 	}
 
-	r, err := rp.GetRegistryByShortURL(t)
-	if err != nil {
-		return nil, err
-	}
+	resolve := func() ([]string, string, error) {
+		r, err := rp.GetRegistryByShortURL(t)
+		if err != nil {
+			return nil, "", err
+		}
 
-	if r == nil {
-		panic(fmt.Errorf("cannot get github registry for %s", t))
-	}
+		if r == nil {
+			panic(fmt.Errorf("cannot get github registry for %s", t))
+		}
 
-	tt, err := NewType(m[3], m[4], m[5])
-	if err != nil {
-		return nil, err
+		constraint := m[5]
+		var tt *Type
+		if IsVersionConstraint(constraint) {
+			version, verr := ResolveVersionConstraint(r, m[3], m[4], constraint)
+			if verr != nil {
+				return nil, "", verr
+			}
+
+			if tt, err = NewVersionedType(m[3], m[4], version, constraint); err != nil {
+				return nil, "", err
+			}
+		} else {
+			if tt, err = NewType(m[3], m[4], constraint); err != nil {
+				return nil, "", err
+			}
+		}
+
+		urls, err := r.GetDownloadURLs(tt)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return util.ConvertURLsToStrings(urls), r.GetRegistryName(), nil
 	}
 
-	urls, err := r.GetDownloadURLs(tt)
-	if err != nil {
-		return nil, err
+	if c := getActiveCache(); c != nil {
+		return c.ResolveDownloadURLs(t, resolve)
 	}
 
-	return util.ConvertURLsToStrings(urls), err
+	urls, _, err := resolve()
+	return urls, err
 }
 
 // ShortTypeToPackageDownloadURLs converts a github URL into downloadable URLs from github.
@@ -239,20 +342,29 @@ func ShortTypeToPackageDownloadURLs(rp RegistryProvider, t string) ([]string, er
 		return nil, fmt.Errorf("Failed to parse short github url: %s", t)
 	}
 
-	r, err := rp.GetRegistryByShortURL(t)
-	if err != nil {
-		return nil, err
-	}
+	resolve := func() ([]string, string, error) {
+		r, err := rp.GetRegistryByShortURL(t)
+		if err != nil {
+			return nil, "", err
+		}
 
-	tt, err := NewType("", m[3], "")
-	if err != nil {
-		return nil, err
+		tt, err := NewType("", m[3], "")
+		if err != nil {
+			return nil, "", err
+		}
+
+		urls, err := r.GetDownloadURLs(tt)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return util.ConvertURLsToStrings(urls), r.GetRegistryName(), nil
 	}
 
-	urls, err := r.GetDownloadURLs(tt)
-	if err != nil {
-		return nil, err
+	if c := getActiveCache(); c != nil {
+		return c.ResolveDownloadURLs(t, resolve)
 	}
 
-	return util.ConvertURLsToStrings(urls), err
+	urls, _, err := resolve()
+	return urls, err
 }
@@ -0,0 +1,170 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kubernetes/deployment-manager/common"
+)
+
+// newTestRegistryProvider returns a *registryProvider backed by rs. Callers
+// register any backend their registries use via RegisterRegistryBackend
+// themselves.
+func newTestRegistryProvider(t *testing.T, rs common.RegistryService) *registryProvider {
+	t.Helper()
+
+	rp := NewRegistryProvider(rs, nil, nil)
+	prp, ok := rp.(*registryProvider)
+	if !ok {
+		t.Fatalf("NewRegistryProvider returned %T, want *registryProvider", rp)
+	}
+
+	return prp
+}
+
+// TestGetRegistryByShortURLConcurrent hammers GetRegistryByShortURL from
+// many goroutines at once. It exists to catch the RLock-then-mutate race
+// that predated the double-checked locking in GetRegistryByShortURL; run
+// with -race to verify it.
+func TestGetRegistryByShortURLConcurrent(t *testing.T) {
+	RegisterRegistryBackend(fakeBackendType, RegistryBackendFactory(func(cr common.Registry) (Registry, error) {
+		return &fakeRegistry{name: cr.Name, shortURL: cr.URL}, nil
+	}), fakeURLMatcher{})
+
+	rs := &fakeRegistryService{
+		byURL: map[string]*common.Registry{
+			"faketype://storage/redis:v1": {Name: "concurrent-registry", Type: fakeBackendType, URL: "faketype://storage/redis:v1"},
+		},
+	}
+
+	rp := newTestRegistryProvider(t, rs)
+	defer rp.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			r, err := rp.GetRegistryByShortURL("faketype://storage/redis:v1")
+			if err != nil {
+				errs <- err
+				return
+			}
+			if r.GetRegistryName() != "concurrent-registry" {
+				errs <- fmt.Errorf("GetRegistryName() = %s, want concurrent-registry", r.GetRegistryName())
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// TestGetRegistryByNameConcurrent is the GetRegistryByName analogue of
+// TestGetRegistryByShortURLConcurrent.
+func TestGetRegistryByNameConcurrent(t *testing.T) {
+	RegisterRegistryBackend(fakeBackendType, RegistryBackendFactory(func(cr common.Registry) (Registry, error) {
+		return &fakeRegistry{name: cr.Name, shortURL: cr.URL}, nil
+	}), fakeURLMatcher{})
+
+	rs := &fakeRegistryService{
+		byName: map[string]*common.Registry{
+			"concurrent-registry": {Name: "concurrent-registry", Type: fakeBackendType, URL: "faketype://storage/redis:v1"},
+		},
+	}
+
+	rp := newTestRegistryProvider(t, rs)
+	defer rp.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			r, err := rp.GetRegistryByName("concurrent-registry")
+			if err != nil {
+				errs <- err
+				return
+			}
+			if r.GetRegistryShortURL() != "faketype://storage/redis:v1" {
+				errs <- fmt.Errorf("GetRegistryShortURL() = %s, want faketype://storage/redis:v1", r.GetRegistryShortURL())
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// TestWatchLoopEvictsRegistryAndVersionCache verifies that an incoming
+// RegistryEvent both drops the cached Registry and clears any
+// versionCache entries resolved for it, so a hot-reload is actually
+// observable rather than just updating rp.registries.
+func TestWatchLoopEvictsRegistryAndVersionCache(t *testing.T) {
+	rp := newTestRegistryProvider(t, &fakeRegistryService{})
+	defer rp.Close()
+
+	rp.Lock()
+	rp.registries["watched-registry"] = &fakeRegistry{name: "watched-registry", shortURL: "faketype://storage/redis:v1"}
+	rp.Unlock()
+
+	versionCacheMu.Lock()
+	versionCache["watched-registry|storage|redis|^1.2"] = versionCacheEntry{
+		version:      "v1.2.5",
+		registryName: "watched-registry",
+		cachedAt:     time.Now(),
+	}
+	versionCacheMu.Unlock()
+
+	events := make(chan RegistryEvent, 1)
+	events <- RegistryEvent{Type: RegistryEventUpdated, Registry: common.Registry{Name: "watched-registry"}}
+	close(events)
+
+	rp.watchLoop(events)
+
+	rp.RLock()
+	_, stillCached := rp.registries["watched-registry"]
+	rp.RUnlock()
+	if stillCached {
+		t.Error("watchLoop did not evict the updated registry from rp.registries")
+	}
+
+	versionCacheMu.Lock()
+	_, stillHasVersion := versionCache["watched-registry|storage|redis|^1.2"]
+	versionCacheMu.Unlock()
+	if stillHasVersion {
+		t.Error("watchLoop did not evict the updated registry's cached version resolution")
+	}
+}
@@ -0,0 +1,300 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VersionLister is implemented by Registry backends that can enumerate the
+// versions available for a given qualifier/type, such as
+// "redis" under "storage". A Registry that does not implement VersionLister
+// falls back to githubTagVersionLister when its short URL looks like a
+// github repository, which covers the built-in github registry types
+// without requiring them to implement VersionLister themselves.
+type VersionLister interface {
+	ListVersions(qualifier, typeName string) ([]string, error)
+}
+
+// IsVersionConstraint returns whether version looks like a semver range
+// rather than an exact version, e.g. "^1.2", "~1.2.3" or "*". Bare versions
+// like "v1" or "1.2.3" are treated as exact and bypass resolution entirely.
+func IsVersionConstraint(version string) bool {
+	return version == "*" || strings.HasPrefix(version, "^") || strings.HasPrefix(version, "~")
+}
+
+// versionCacheTTL bounds how long a resolved constraint is trusted before
+// ResolveVersionConstraint re-lists versions, even absent a hot-reload
+// event. It defaults to 5 minutes; set it with SetVersionCacheTTL.
+var versionCacheTTL = 5 * time.Minute
+
+// SetVersionCacheTTL changes how long resolved version constraints are
+// cached for. A ttl of zero disables time-based expiry; resolutions are
+// still evicted on registry hot-reload events via registrywatch.go.
+func SetVersionCacheTTL(ttl time.Duration) {
+	versionCacheMu.Lock()
+	defer versionCacheMu.Unlock()
+
+	versionCacheTTL = ttl
+}
+
+type versionCacheEntry struct {
+	version      string
+	registryName string
+	cachedAt     time.Time
+}
+
+var (
+	versionCacheMu sync.Mutex
+	versionCache   = map[string]versionCacheEntry{}
+)
+
+// ResolveVersionConstraint resolves constraint (e.g. "^1.2", "~1.2.3", "*")
+// against the versions available for qualifier/typeName, returning the
+// highest matching concrete version. If r implements VersionLister, its
+// ListVersions is used; otherwise, if r's short URL identifies a github
+// repository, versions are listed from that repository's tags. Results are
+// cached by (registry, qualifier, typeName, constraint) for
+// versionCacheTTL, and are evicted immediately when registrywatch.go
+// observes that registry change.
+func ResolveVersionConstraint(r Registry, qualifier, typeName, constraint string) (string, error) {
+	key := strings.Join([]string{r.GetRegistryName(), qualifier, typeName, constraint}, "|")
+
+	versionCacheMu.Lock()
+	entry, ok := versionCache[key]
+	ttl := versionCacheTTL
+	versionCacheMu.Unlock()
+
+	if ok && (ttl <= 0 || time.Since(entry.cachedAt) < ttl) {
+		return entry.version, nil
+	}
+
+	vl, ok := r.(VersionLister)
+	if !ok {
+		owner, repo, isGithub := parseGithubOwnerRepo(r.GetRegistryShortURL())
+		if !isGithub {
+			return "", fmt.Errorf("registry %s cannot list versions for %s/%s", r.GetRegistryName(), qualifier, typeName)
+		}
+
+		vl = &githubTagVersionLister{owner: owner, repo: repo, client: http.DefaultClient}
+	}
+
+	versions, err := vl.ListVersions(qualifier, typeName)
+	if err != nil {
+		return "", fmt.Errorf("cannot list versions for %s/%s: %s", qualifier, typeName, err)
+	}
+
+	resolved, err := highestMatching(versions, constraint)
+	if err != nil {
+		return "", fmt.Errorf("no version of %s/%s satisfies %s: %s", qualifier, typeName, constraint, err)
+	}
+
+	versionCacheMu.Lock()
+	versionCache[key] = versionCacheEntry{version: resolved, registryName: r.GetRegistryName(), cachedAt: time.Now()}
+	versionCacheMu.Unlock()
+
+	return resolved, nil
+}
+
+// InvalidateVersionCache clears every cached constraint resolution.
+func InvalidateVersionCache() {
+	versionCacheMu.Lock()
+	defer versionCacheMu.Unlock()
+
+	versionCache = map[string]versionCacheEntry{}
+}
+
+// InvalidateVersionCacheForRegistry clears cached constraint resolutions
+// belonging to the named registry. registrywatch.go calls this whenever it
+// observes an add/update/delete event for that registry, so a newly
+// published version is picked up immediately rather than waiting out
+// versionCacheTTL.
+func InvalidateVersionCacheForRegistry(name string) {
+	versionCacheMu.Lock()
+	defer versionCacheMu.Unlock()
+
+	for key, entry := range versionCache {
+		if entry.registryName == name {
+			delete(versionCache, key)
+		}
+	}
+}
+
+// githubOwnerRepoMatcher recognizes a github repository short URL, e.g.
+// "github.com/owner/repo" or "https://github.com/owner/repo".
+var githubOwnerRepoMatcher = regexp.MustCompile(`^(?:https?://)?github\.com/([^/]+)/([^/]+)`)
+
+// parseGithubOwnerRepo extracts the owner and repo from a github short URL.
+func parseGithubOwnerRepo(shortURL string) (owner, repo string, ok bool) {
+	m := githubOwnerRepoMatcher.FindStringSubmatch(shortURL)
+	if m == nil {
+		return "", "", false
+	}
+
+	return m[1], m[2], true
+}
+
+// githubTagVersionLister implements VersionLister by listing a github
+// repository's tags. It is used as a fallback for registries that don't
+// implement VersionLister themselves but are backed by github.
+type githubTagVersionLister struct {
+	owner, repo string
+	client      *http.Client
+}
+
+// githubTag is the subset of the github tags API response we need.
+type githubTag struct {
+	Name string `json:"name"`
+}
+
+func (g *githubTagVersionLister) ListVersions(qualifier, typeName string) ([]string, error) {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/tags", g.owner, g.repo)
+
+	resp, err := g.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("github returned %s for %s", resp.Status, url)
+	}
+
+	var tags []githubTag
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, len(tags))
+	for i, t := range tags {
+		versions[i] = t.Name
+	}
+
+	return versions, nil
+}
+
+// semver is a parsed major.minor.patch version. Pre-release and build
+// metadata are not supported; versions that carry them are rejected.
+type semver struct {
+	major, minor, patch int
+}
+
+func parseSemver(v string) (semver, error) {
+	v = strings.TrimPrefix(v, "v")
+	parts := strings.SplitN(v, ".", 3)
+
+	var s semver
+	var err error
+
+	if s.major, err = strconv.Atoi(parts[0]); err != nil {
+		return semver{}, fmt.Errorf("invalid version %s", v)
+	}
+
+	if len(parts) > 1 {
+		if s.minor, err = strconv.Atoi(parts[1]); err != nil {
+			return semver{}, fmt.Errorf("invalid version %s", v)
+		}
+	}
+
+	if len(parts) > 2 {
+		if s.patch, err = strconv.Atoi(parts[2]); err != nil {
+			return semver{}, fmt.Errorf("invalid version %s", v)
+		}
+	}
+
+	return s, nil
+}
+
+// less reports whether s sorts before o.
+func (s semver) less(o semver) bool {
+	if s.major != o.major {
+		return s.major < o.major
+	}
+	if s.minor != o.minor {
+		return s.minor < o.minor
+	}
+	return s.patch < o.patch
+}
+
+// satisfies reports whether s satisfies constraint, which is "*", a caret
+// range ("^1.2"), or a tilde range ("~1.2.3").
+func (s semver) satisfies(constraint string) (bool, error) {
+	if constraint == "*" {
+		return true, nil
+	}
+
+	if strings.HasPrefix(constraint, "^") {
+		base, err := parseSemver(constraint[1:])
+		if err != nil {
+			return false, err
+		}
+
+		upper := semver{major: base.major + 1}
+		return !s.less(base) && s.less(upper), nil
+	}
+
+	if strings.HasPrefix(constraint, "~") {
+		base, err := parseSemver(constraint[1:])
+		if err != nil {
+			return false, err
+		}
+
+		upper := semver{major: base.major, minor: base.minor + 1}
+		return !s.less(base) && s.less(upper), nil
+	}
+
+	return false, fmt.Errorf("unrecognized version constraint %s", constraint)
+}
+
+// highestMatching returns the highest version in versions that satisfies
+// constraint.
+func highestMatching(versions []string, constraint string) (string, error) {
+	var best semver
+	var bestRaw string
+	found := false
+
+	for _, raw := range versions {
+		v, err := parseSemver(raw)
+		if err != nil {
+			continue
+		}
+
+		ok, err := v.satisfies(constraint)
+		if err != nil {
+			return "", err
+		}
+
+		if ok && (!found || best.less(v)) {
+			best = v
+			bestRaw = raw
+			found = true
+		}
+	}
+
+	if !found {
+		return "", fmt.Errorf("no matching version among %v", versions)
+	}
+
+	return bestRaw, nil
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import "testing"
+
+func TestSatisfies(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		want       bool
+	}{
+		{"v1.2.5", "*", true},
+		{"v1.2.5", "^1.2", true},
+		{"v1.3.0", "^1.2", true},
+		{"v2.0.0", "^1.2", false},
+		{"v1.1.9", "^1.2", false},
+		{"v1.2.5", "~1.2.3", true},
+		{"v1.3.0", "~1.2.3", false},
+		{"v1.2.2", "~1.2.3", false},
+	}
+
+	for _, tc := range tests {
+		v, err := parseSemver(tc.version)
+		if err != nil {
+			t.Fatalf("parseSemver(%s): %s", tc.version, err)
+		}
+
+		got, err := v.satisfies(tc.constraint)
+		if err != nil {
+			t.Fatalf("satisfies(%s, %s): %s", tc.version, tc.constraint, err)
+		}
+
+		if got != tc.want {
+			t.Errorf("satisfies(%s, %s) = %v, want %v", tc.version, tc.constraint, got, tc.want)
+		}
+	}
+}
+
+func TestSatisfiesUnrecognizedConstraint(t *testing.T) {
+	v, err := parseSemver("v1.2.5")
+	if err != nil {
+		t.Fatalf("parseSemver: %s", err)
+	}
+
+	if _, err := v.satisfies("1.2.5"); err == nil {
+		t.Error("satisfies with a bare version constraint: expected error, got nil")
+	}
+}
+
+func TestHighestMatching(t *testing.T) {
+	versions := []string{"v1.1.9", "v1.2.0", "v1.2.5", "v1.3.0", "v2.0.0"}
+
+	got, err := highestMatching(versions, "^1.2")
+	if err != nil {
+		t.Fatalf("highestMatching: %s", err)
+	}
+
+	if got != "v1.3.0" {
+		t.Errorf("highestMatching(%v, \"^1.2\") = %s, want v1.3.0", versions, got)
+	}
+}
+
+func TestHighestMatchingNoMatch(t *testing.T) {
+	versions := []string{"v0.9.0", "v2.0.0"}
+
+	if _, err := highestMatching(versions, "^1.2"); err == nil {
+		t.Error("highestMatching with no satisfying version: expected error, got nil")
+	}
+}
+
+func TestHighestMatchingSkipsUnparseableVersions(t *testing.T) {
+	versions := []string{"not-a-version", "v1.2.0", "v1.2.7"}
+
+	got, err := highestMatching(versions, "^1.2")
+	if err != nil {
+		t.Fatalf("highestMatching: %s", err)
+	}
+
+	if got != "v1.2.7" {
+		t.Errorf("highestMatching(%v, \"^1.2\") = %s, want v1.2.7", versions, got)
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import "fmt"
+
+// Type identifies a template or package within a registry: its qualifier
+// (e.g. "storage"), its name (e.g. "redis") and the concrete version to
+// fetch. Constraint is set alongside Version when Version was chosen by
+// resolving a semver range such as "^1.2" rather than given outright, so
+// callers can report both what the user asked for and what was resolved.
+type Type struct {
+	Qualifier  string
+	Name       string
+	Version    string
+	Constraint string
+}
+
+// NewType builds a Type for an exact version. Use NewVersionedType instead
+// when version was resolved from a semver range constraint.
+func NewType(qualifier, name, version string) (*Type, error) {
+	if name == "" {
+		return nil, fmt.Errorf("type name must not be empty")
+	}
+
+	return &Type{Qualifier: qualifier, Name: name, Version: version}, nil
+}
+
+// NewVersionedType builds a Type whose Version was resolved from
+// constraint, a semver range such as "^1.2", "~1.2.3" or "*". Both the raw
+// constraint and the resolved concrete version are retained.
+func NewVersionedType(qualifier, name, version, constraint string) (*Type, error) {
+	t, err := NewType(qualifier, name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Constraint = constraint
+	return t, nil
+}